@@ -0,0 +1,74 @@
+package main
+
+import "sync"
+
+// AllocMap hands out generational handles for values of type T. Each handle
+// packs a slot index and a generation counter into a single int: the low 32
+// bits are the slot, the high 32 bits are the generation that slot was
+// allocated at. Freeing a slot bumps its generation and returns the slot to
+// a free-list for reuse, so long-lived embedders that allocate and free many
+// short-lived values (ephemeral test servers, message iterators, event
+// subscriptions) don't grow the backing slice unboundedly. resolve rejects a
+// handle whose generation no longer matches the slot's current generation,
+// so a stale handle from a freed (and possibly reallocated) slot never
+// silently resolves to the wrong value.
+type AllocMap[T any] struct {
+	mu          sync.RWMutex
+	instances   []*T
+	generations []uint32
+	freeSlots   []uint32
+}
+
+func packHandle(slot, generation uint32) int {
+	return int(uint64(generation)<<32 | uint64(slot))
+}
+
+func unpackHandle(h int) (slot, generation uint32) {
+	u := uint64(h)
+	return uint32(u), uint32(u >> 32)
+}
+
+func (a *AllocMap[T]) alloc(i *T) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if n := len(a.freeSlots); n > 0 {
+		slot := a.freeSlots[n-1]
+		a.freeSlots = a.freeSlots[:n-1]
+		a.instances[slot] = i
+
+		return packHandle(slot, a.generations[slot])
+	}
+
+	slot := uint32(len(a.instances))
+	a.instances = append(a.instances, i)
+	a.generations = append(a.generations, 0)
+
+	return packHandle(slot, 0)
+}
+
+func (a *AllocMap[T]) free(h int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	slot, generation := unpackHandle(h)
+	if int(slot) >= len(a.instances) || a.generations[slot] != generation || a.instances[slot] == nil {
+		return
+	}
+
+	a.instances[slot] = nil
+	a.generations[slot]++
+	a.freeSlots = append(a.freeSlots, slot)
+}
+
+func (a *AllocMap[T]) resolve(h int) *T {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	slot, generation := unpackHandle(h)
+	if int(slot) >= len(a.generations) || a.generations[slot] != generation {
+		return nil
+	}
+
+	return a.instances[slot]
+}
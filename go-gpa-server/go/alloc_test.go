@@ -0,0 +1,97 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestAllocMapResolveRoundTrip(t *testing.T) {
+	var m AllocMap[int]
+
+	v := 42
+	h := m.alloc(&v)
+
+	got := m.resolve(h)
+	if got == nil || *got != 42 {
+		t.Fatalf("resolve(%d) = %v, want pointer to 42", h, got)
+	}
+}
+
+func TestAllocMapABA(t *testing.T) {
+	var m AllocMap[int]
+
+	a, b := 1, 2
+	h1 := m.alloc(&a)
+	m.free(h1)
+
+	h2 := m.alloc(&b)
+
+	slot1, _ := unpackHandle(h1)
+	slot2, _ := unpackHandle(h2)
+	if slot1 != slot2 {
+		t.Fatalf("expected freed slot %d to be reused, got new slot %d", slot1, slot2)
+	}
+
+	if got := m.resolve(h1); got != nil {
+		t.Fatalf("resolve(h1) = %v after slot reuse, want nil (stale generation)", got)
+	}
+
+	if got := m.resolve(h2); got == nil || *got != 2 {
+		t.Fatalf("resolve(h2) = %v, want pointer to 2", got)
+	}
+}
+
+func TestAllocMapDoubleFree(t *testing.T) {
+	var m AllocMap[int]
+
+	v := 7
+	h := m.alloc(&v)
+
+	m.free(h)
+	m.free(h) // must not panic or corrupt the free-list
+
+	if got := m.resolve(h); got != nil {
+		t.Fatalf("resolve(h) = %v after free, want nil", got)
+	}
+
+	w := 8
+	h2 := m.alloc(&w)
+	if got := m.resolve(h2); got == nil || *got != 8 {
+		t.Fatalf("resolve(h2) = %v, want pointer to 8", got)
+	}
+}
+
+func TestAllocMapConcurrentAllocResolve(t *testing.T) {
+	var m AllocMap[int]
+
+	const workers = 32
+	const perWorker = 200
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < perWorker; i++ {
+				v := i
+				h := m.alloc(&v)
+
+				if got := m.resolve(h); got == nil {
+					t.Errorf("resolve(%d) = nil right after alloc", h)
+					return
+				}
+
+				m.free(h)
+
+				if got := m.resolve(h); got != nil {
+					t.Errorf("resolve(%d) = %v after free, want nil", h, got)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,187 @@
+package main
+
+/*
+#include <stdlib.h>
+
+typedef void (*gpa_panic_cb)(const char *msg, void *userdata);
+typedef void (*gpa_event_cb)(const char *json, void *userdata);
+
+static inline void gpa_invoke_panic_cb(gpa_panic_cb cb, const char *msg, void *userdata) {
+	cb(msg, userdata);
+}
+
+static inline void gpa_invoke_event_cb(gpa_event_cb cb, const char *json, void *userdata) {
+	cb(json, userdata);
+}
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// eventQueueLen bounds how many pending notifications a single event
+// subscriber may have outstanding. A slow or wedged C callback must not be
+// able to stall the server goroutine producing events, so once the queue is
+// full new events are dropped rather than blocking the producer.
+const eventQueueLen = 256
+
+var gPanicHandler struct {
+	sync.Mutex
+	cb       C.gpa_panic_cb
+	userdata unsafe.Pointer
+}
+
+// gpaSetPanicHandler registers cb to be invoked, from a dedicated goroutine,
+// whenever a recovered panic would otherwise have crashed the host process
+// opaquely. Pass a nil cb to unregister.
+//
+//export gpaSetPanicHandler
+func gpaSetPanicHandler(cb C.gpa_panic_cb, userdata unsafe.Pointer) {
+	gPanicHandler.Lock()
+	defer gPanicHandler.Unlock()
+
+	gPanicHandler.cb = cb
+	gPanicHandler.userdata = userdata
+}
+
+// reportPanic dispatches r to the registered panic handler, if any, on its
+// own goroutine so a handler that blocks (or re-panics) cannot wedge the
+// caller that recovered the original panic.
+func reportPanic(r any) {
+	gPanicHandler.Lock()
+	cb := gPanicHandler.cb
+	userdata := gPanicHandler.userdata
+	gPanicHandler.Unlock()
+
+	if cb == nil {
+		return
+	}
+
+	msg := C.CString(fmt.Sprintf("%v", r))
+
+	go func() {
+		defer C.free(unsafe.Pointer(msg))
+		C.gpa_invoke_panic_cb(cb, msg, userdata)
+	}()
+}
+
+// guarded recovers any panic raised by fn, reports it to the registered
+// panic handler, and turns it into the ordinary -1 failure status instead of
+// letting it unwind into the C host.
+func guarded(fn func() int) (ret int) {
+	defer func() {
+		if r := recover(); r != nil {
+			reportPanic(r)
+			ret = -1
+		}
+	}()
+
+	return fn()
+}
+
+type eventSub struct {
+	queue chan []byte
+	stop  chan struct{}
+}
+
+var eventSubs struct {
+	sync.Mutex
+	byHandle map[int]*eventSub
+}
+
+func init() {
+	eventSubs.byHandle = make(map[int]*eventSub)
+}
+
+// gpaSetEventCallback registers cb to receive JSON-encoded server events
+// observed on the server behind h (user/address/label creation, message
+// label and starred changes, revocations, and injected events), so a C
+// host can assert on server-observed behaviour without polling. No event is
+// emitted for quota changes or message imports, as this FFI exposes neither
+// operation. Events are
+// always dispatched from a dedicated goroutine over a bounded queue so a
+// slow callback cannot block the server. Pass a nil cb to unregister.
+//
+//export gpaSetEventCallback
+func gpaSetEventCallback(h int, cb C.gpa_event_cb, userdata unsafe.Pointer) int {
+	eventSubs.Lock()
+	defer eventSubs.Unlock()
+
+	removeEventSubLocked(h)
+
+	if cb == nil {
+		return 0
+	}
+
+	sub := &eventSub{
+		queue: make(chan []byte, eventQueueLen),
+		stop:  make(chan struct{}),
+	}
+	eventSubs.byHandle[h] = sub
+
+	go func() {
+		for {
+			select {
+			case payload := <-sub.queue:
+				cjson := C.CString(string(payload))
+				C.gpa_invoke_event_cb(cb, cjson, userdata)
+				C.free(unsafe.Pointer(cjson))
+			case <-sub.stop:
+				return
+			}
+		}
+	}()
+
+	return 0
+}
+
+// removeEventSubLocked stops and discards h's event subscriber, if any. The
+// caller must hold eventSubs.Mutex.
+func removeEventSubLocked(h int) {
+	if old, ok := eventSubs.byHandle[h]; ok {
+		close(old.stop)
+		delete(eventSubs.byHandle, h)
+	}
+}
+
+// removeEventSub is removeEventSubLocked with its own locking, for callers
+// (such as gpaServerDelete) outside this file that don't already hold
+// eventSubs.Mutex.
+func removeEventSub(h int) {
+	eventSubs.Lock()
+	defer eventSubs.Unlock()
+
+	removeEventSubLocked(h)
+}
+
+// notifyEvent best-effort delivers a {"kind": kind, ...fields} JSON payload
+// to h's registered event subscriber, if any. The event is dropped, rather
+// than blocking the caller, if the subscriber's queue is full.
+func notifyEvent(h int, kind string, fields map[string]any) {
+	eventSubs.Lock()
+	sub := eventSubs.byHandle[h]
+	eventSubs.Unlock()
+
+	if sub == nil {
+		return
+	}
+
+	payload := map[string]any{"kind": kind}
+	for k, v := range fields {
+		payload[k] = v
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	select {
+	case sub.queue <- encoded:
+	default:
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import "C"
+
+import "sync"
+
+// lastErrors records the most recent error observed for each server handle
+// so that C callers can recover a human-readable message after an export
+// returns a negative status code. Exports that only ever dealt in handles
+// and raw bools previously had no way to explain a failure beyond "-1"; this
+// gives them one without changing any existing signature.
+var lastErrors struct {
+	sync.Mutex
+	byHandle map[int]error
+}
+
+func init() {
+	lastErrors.byHandle = make(map[int]error)
+}
+
+// setLastError records err against h and returns code, so call sites can
+// write `return setLastError(h, -1, err)`.
+func setLastError(h int, code int, err error) int {
+	lastErrors.Lock()
+	defer lastErrors.Unlock()
+
+	lastErrors.byHandle[h] = err
+	return code
+}
+
+func clearLastError(h int) {
+	lastErrors.Lock()
+	defer lastErrors.Unlock()
+
+	delete(lastErrors.byHandle, h)
+}
+
+//export gpaLastError
+func gpaLastError(h int) *C.char {
+	lastErrors.Lock()
+	err := lastErrors.byHandle[h]
+	lastErrors.Unlock()
+
+	if err == nil {
+		return nil
+	}
+
+	return C.CString(err.Error())
+}
@@ -0,0 +1,246 @@
+package main
+
+/*
+#include <stdint.h>
+
+#include "cgo_types.h"
+*/
+import "C"
+
+import (
+	proton "github.com/ProtonMail/go-proton-api"
+)
+
+// gpaCreateAddress adds an additional address to an already-created user and
+// reports its address ID via outAddrID (caller frees with CStrFree).
+//
+//export gpaCreateAddress
+func gpaCreateAddress(h int, cuserID, cemail, cpassword *C.cchar_t, outAddrID **C.char) int {
+	return guarded(func() int {
+		srv := alloc.resolve(h)
+		if srv == nil {
+			return -1
+		}
+
+		userID := C.GoString(cuserID)
+		email := C.GoString(cemail)
+		password := []byte(C.GoString(cpassword))
+
+		addrID, err := srv.CreateAddress(userID, email, password)
+		if err != nil {
+			return setLastError(h, -1, err)
+		}
+
+		*outAddrID = C.CString(addrID)
+
+		notifyEvent(h, "address_created", map[string]any{"userID": userID, "addrID": addrID})
+
+		return 0
+	})
+}
+
+// gpaCreateLabel creates a label or folder for a user. kind follows
+// proton.LabelType (1 = label, 3 = folder); parentID may be empty.
+//
+//export gpaCreateLabel
+func gpaCreateLabel(h int, cuserID, cname, cparentID *C.cchar_t, kind int, outLabelID **C.char) int {
+	return guarded(func() int {
+		srv := alloc.resolve(h)
+		if srv == nil {
+			return -1
+		}
+
+		userID := C.GoString(cuserID)
+		name := C.GoString(cname)
+		parentID := C.GoString(cparentID)
+
+		labelID, err := srv.CreateLabel(userID, name, parentID, proton.LabelType(kind))
+		if err != nil {
+			return setLastError(h, -1, err)
+		}
+
+		*outLabelID = C.CString(labelID)
+
+		notifyEvent(h, "label_created", map[string]any{"userID": userID, "labelID": labelID, "labelType": kind})
+
+		return 0
+	})
+}
+
+// gpaLabelMessage applies labelID to messageID, which is how the upstream
+// test server represents both user-created labels/folders and the
+// "starred" system label (proton.StarredLabel). There is no test-server
+// equivalent for the unread/read flag: backend.Backend.SetMessagesRead
+// exists but is not exposed on server.Server, so seen/unseen state can't be
+// driven through this FFI.
+//
+//export gpaLabelMessage
+func gpaLabelMessage(h int, cuserID, cmessageID, clabelID *C.cchar_t) int {
+	return guarded(func() int {
+		srv := alloc.resolve(h)
+		if srv == nil {
+			return -1
+		}
+
+		userID := C.GoString(cuserID)
+		messageID := C.GoString(cmessageID)
+		labelID := C.GoString(clabelID)
+
+		if err := srv.LabelMessage(userID, messageID, labelID); err != nil {
+			return setLastError(h, -1, err)
+		}
+
+		notifyEvent(h, "message_labelled", map[string]any{"userID": userID, "messageID": messageID, "labelID": labelID})
+
+		return 0
+	})
+}
+
+// gpaUnlabelMessage is the inverse of gpaLabelMessage.
+//
+//export gpaUnlabelMessage
+func gpaUnlabelMessage(h int, cuserID, cmessageID, clabelID *C.cchar_t) int {
+	return guarded(func() int {
+		srv := alloc.resolve(h)
+		if srv == nil {
+			return -1
+		}
+
+		userID := C.GoString(cuserID)
+		messageID := C.GoString(cmessageID)
+		labelID := C.GoString(clabelID)
+
+		if err := srv.UnlabelMessage(userID, messageID, labelID); err != nil {
+			return setLastError(h, -1, err)
+		}
+
+		notifyEvent(h, "message_unlabelled", map[string]any{"userID": userID, "messageID": messageID, "labelID": labelID})
+
+		return 0
+	})
+}
+
+// gpaSetMessageStarred toggles proton.StarredLabel on messageID, the
+// closest real equivalent the test server offers to a generic message-flag
+// setter.
+//
+//export gpaSetMessageStarred
+func gpaSetMessageStarred(h int, cuserID, cmessageID *C.cchar_t, starred int) int {
+	return guarded(func() int {
+		srv := alloc.resolve(h)
+		if srv == nil {
+			return -1
+		}
+
+		userID := C.GoString(cuserID)
+		messageID := C.GoString(cmessageID)
+
+		var err error
+		if starred != 0 {
+			err = srv.LabelMessage(userID, messageID, proton.StarredLabel)
+		} else {
+			err = srv.UnlabelMessage(userID, messageID, proton.StarredLabel)
+		}
+		if err != nil {
+			return setLastError(h, -1, err)
+		}
+
+		notifyEvent(h, "message_starred_changed", map[string]any{"userID": userID, "messageID": messageID, "starred": starred != 0})
+
+		return 0
+	})
+}
+
+// gpaRevokeUser revokes every active session belonging to userID, forcing
+// any bridge client to re-authenticate on its next request.
+//
+//export gpaRevokeUser
+func gpaRevokeUser(h int, cuserID *C.cchar_t) int {
+	return guarded(func() int {
+		srv := alloc.resolve(h)
+		if srv == nil {
+			return -1
+		}
+
+		userID := C.GoString(cuserID)
+
+		if err := srv.RevokeUser(userID); err != nil {
+			return setLastError(h, -1, err)
+		}
+
+		notifyEvent(h, "user_revoked", map[string]any{"userID": userID})
+
+		return 0
+	})
+}
+
+// gpaAddAddressCreatedEvent, gpaAddLabelCreatedEvent and
+// gpaAddMessageCreatedEvent push a real event of the named kind onto
+// userID's event stream via the matching server.Server helper, letting C
+// hosts assert on server-observed behaviour without polling. There is no
+// generic "push arbitrary event" entry point: the test server only
+// generates events through these three helpers (and through the fixture
+// calls above, which already call them internally where upstream does).
+
+//export gpaAddAddressCreatedEvent
+func gpaAddAddressCreatedEvent(h int, cuserID, caddrID *C.cchar_t) int {
+	return guarded(func() int {
+		srv := alloc.resolve(h)
+		if srv == nil {
+			return -1
+		}
+
+		userID := C.GoString(cuserID)
+		addrID := C.GoString(caddrID)
+
+		if err := srv.AddAddressCreatedEvent(userID, addrID); err != nil {
+			return setLastError(h, -1, err)
+		}
+
+		notifyEvent(h, "event_pushed", map[string]any{"userID": userID, "event": "address_created"})
+
+		return 0
+	})
+}
+
+//export gpaAddLabelCreatedEvent
+func gpaAddLabelCreatedEvent(h int, cuserID, clabelID *C.cchar_t) int {
+	return guarded(func() int {
+		srv := alloc.resolve(h)
+		if srv == nil {
+			return -1
+		}
+
+		userID := C.GoString(cuserID)
+		labelID := C.GoString(clabelID)
+
+		if err := srv.AddLabelCreatedEvent(userID, labelID); err != nil {
+			return setLastError(h, -1, err)
+		}
+
+		notifyEvent(h, "event_pushed", map[string]any{"userID": userID, "event": "label_created"})
+
+		return 0
+	})
+}
+
+//export gpaAddMessageCreatedEvent
+func gpaAddMessageCreatedEvent(h int, cuserID, cmessageID *C.cchar_t) int {
+	return guarded(func() int {
+		srv := alloc.resolve(h)
+		if srv == nil {
+			return -1
+		}
+
+		userID := C.GoString(cuserID)
+		messageID := C.GoString(cmessageID)
+
+		if err := srv.AddMessageCreatedEvent(userID, messageID); err != nil {
+			return setLastError(h, -1, err)
+		}
+
+		notifyEvent(h, "event_pushed", map[string]any{"userID": userID, "event": "message_created"})
+
+		return 0
+	})
+}
@@ -4,104 +4,86 @@ package main
 #include <stdint.h>
 #include <stdlib.h>
 
-typedef const char cchar_t;
+#include "cgo_types.h"
 */
 import "C"
 import (
-	"sync"
 	"unsafe"
 
 	"github.com/ProtonMail/go-proton-api/server"
 )
 
-type AllocMap[T any] struct {
-	sync      sync.RWMutex
-	instances []*T
-}
-
-func (a *AllocMap[T]) alloc(i *T) int {
-	a.sync.Lock()
-	defer a.sync.Unlock()
-
-	a.instances = append(a.instances, i)
-
-	return len(a.instances) - 1
-}
-
-func (a *AllocMap[T]) free(i int) {
-	a.sync.Lock()
-	defer a.sync.Unlock()
-
-	a.instances[i] = nil
-}
-
-func (a *AllocMap[T]) resolve(i int) *T {
-	a.sync.RLock()
-	defer a.sync.RUnlock()
-
-	return a.instances[i]
-}
-
 var alloc struct {
-    AllocMap[server.Server]
+	AllocMap[server.Server]
 }
 
-func init() {
-    alloc.sync.Lock();
-    alloc.instances = make([]*server.Server, 0, 20)
-    alloc.sync.Unlock();
-}
-
-
-
 //export gpaServerNew
 func gpaServerNew() int {
-	handle := alloc.alloc(server.New(server.WithTLS(false)))
+	var handle int
+	if status := guarded(func() int {
+		handle = alloc.alloc(server.New(server.WithTLS(false)))
+		return 0
+	}); status != 0 {
+		return -1
+	}
 
 	return handle
 }
 
 //export gpaServerUrl
 func gpaServerUrl(h int) *C.char {
-	srv := alloc.resolve(h)
-	if srv == nil {
-		return nil
-	}
-
-	url := srv.GetHostURL()
-	return C.CString(url)
+	var url *C.char
+	guarded(func() int {
+		srv := alloc.resolve(h)
+		if srv == nil {
+			return -1
+		}
+
+		url = C.CString(srv.GetHostURL())
+		return 0
+	})
+
+	return url
 }
 
 //export gpaServerDelete
 func gpaServerDelete(h int) int {
-	srv := alloc.resolve(h)
-	if srv == nil {
-		return -1
-	}
-
-	srv.Close()
-	alloc.free(h)
-	return 0
+	return guarded(func() int {
+		srv := alloc.resolve(h)
+		if srv == nil {
+			return -1
+		}
+
+		srv.Close()
+		alloc.free(h)
+		clearLastError(h)
+		removeEventSub(h)
+		return 0
+	})
 }
 
 //export gpaCreateUser
 func gpaCreateUser(h int, cuser *C.cchar_t, cpassword *C.cchar_t, outUserID **C.char, outAddrID **C.char) int {
-	user := C.GoString(cuser)
-	password := []byte(C.GoString(cpassword))
-	srv := alloc.resolve(h)
-	if srv == nil {
-		return -1
-	}
-
-	userID, addrID, err := srv.CreateUser(user, password)
-	if err != nil {
-		return -1
-	}
-
-	*outUserID= C.CString(userID)
-	*outAddrID = C.CString(addrID)
-
-	return 0
+	return guarded(func() int {
+		user := C.GoString(cuser)
+		password := []byte(C.GoString(cpassword))
+		srv := alloc.resolve(h)
+		if srv == nil {
+			return -1
+		}
+
+		userID, addrID, err := srv.CreateUser(user, password)
+		if err != nil {
+			return setLastError(h, -1, err)
+		}
+
+		*outUserID = C.CString(userID)
+		*outAddrID = C.CString(addrID)
+
+		notifyEvent(h, "user_created", map[string]any{"userID": userID, "addrID": addrID})
+
+		return 0
+	})
 }
 
 //export CStrFree
@@ -0,0 +1,202 @@
+package main
+
+/*
+#include "cgo_types.h"
+*/
+import "C"
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Snapshot file layout:
+//
+//	magic      [4]byte  "GPAS"
+//	version    byte     1
+//	cipherID   byte     snapshotCipherAESGCM
+//	compressID byte     snapshotCompressNone | snapshotCompressZstd
+//	salt       [snapshotSaltLen]byte   scrypt salt
+//	nonce      [snapshotNonceLen]byte  AES-GCM nonce
+//	ciphertext []byte   remainder of the file
+//
+// The key is derived from the caller-supplied passphrase with scrypt using
+// fixed, deliberately expensive parameters (N=2^15, r=8, p=1). The container
+// treats its plaintext as opaque bytes; no backend state is written into it
+// (see errSnapshotUnsupported).
+const (
+	snapshotMagic    = "GPAS"
+	snapshotVersion  = 1
+	snapshotSaltLen  = 16
+	snapshotNonceLen = 12
+	snapshotKeyLen   = 32
+
+	snapshotCipherAESGCM = 1
+
+	snapshotCompressNone = 0
+	snapshotCompressZstd = 1
+)
+
+var errSnapshotHeader = errors.New("gpa: invalid snapshot header")
+
+func deriveSnapshotKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, snapshotKeyLen)
+}
+
+func encryptSnapshot(plaintext []byte, passphrase string, compress bool) ([]byte, error) {
+	compressID := byte(snapshotCompressNone)
+	if compress {
+		enc, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+		plaintext = enc.EncodeAll(plaintext, nil)
+		enc.Close()
+		compressID = snapshotCompressZstd
+	}
+
+	salt := make([]byte, snapshotSaltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	key, err := deriveSnapshotKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, snapshotNonceLen)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.WriteString(snapshotMagic)
+	out.WriteByte(snapshotVersion)
+	out.WriteByte(snapshotCipherAESGCM)
+	out.WriteByte(compressID)
+	out.Write(salt)
+	out.Write(nonce)
+	out.Write(gcm.Seal(nil, nonce, plaintext, nil))
+
+	return out.Bytes(), nil
+}
+
+func decryptSnapshot(data []byte, passphrase string) ([]byte, error) {
+	headerLen := len(snapshotMagic) + 3 + snapshotSaltLen + snapshotNonceLen
+	if len(data) < headerLen {
+		return nil, errSnapshotHeader
+	}
+
+	if string(data[:4]) != snapshotMagic {
+		return nil, errSnapshotHeader
+	}
+
+	version := data[4]
+	cipherID := data[5]
+	compressID := data[6]
+	if version != snapshotVersion || cipherID != snapshotCipherAESGCM {
+		return nil, errSnapshotHeader
+	}
+
+	off := 7
+	salt := data[off : off+snapshotSaltLen]
+	off += snapshotSaltLen
+	nonce := data[off : off+snapshotNonceLen]
+	off += snapshotNonceLen
+	ciphertext := data[off:]
+
+	key, err := deriveSnapshotKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if compressID == snapshotCompressZstd {
+		dec, err := zstd.NewReader(nil)
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+
+		plaintext, err = dec.DecodeAll(plaintext, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plaintext, nil
+}
+
+// errSnapshotUnsupported is recorded as the last error by
+// gpaServerSaveSnapshot and gpaServerLoadSnapshot for any valid handle;
+// gpaServerNewWithSnapshot has no handle to record it against and simply
+// returns -1. All three fail because
+// go-proton-api's server.Server and server/backend.Backend keep every
+// piece of server state (accounts, messages, labels, the update/event log)
+// in unexported fields with no accessor, and there is no enumeration API
+// (e.g. "list all user IDs") to reconstruct it from outside the package.
+// There is therefore no real state to serialize or restore here, short of
+// forking the upstream dependency. The container format above
+// (encryptSnapshot/decryptSnapshot) is real and tested so it's ready to
+// back these exports the day upstream grows a state accessor; until then
+// they fail clearly instead of pretending to persist anything.
+var errSnapshotUnsupported = errors.New("gpa: snapshotting is not supported by this version of go-proton-api's server.Server (no exported backend state accessor)")
+
+//export gpaServerNewWithSnapshot
+func gpaServerNewWithSnapshot(cpath, cpassphrase *C.cchar_t) int {
+	return -1
+}
+
+//export gpaServerSaveSnapshot
+func gpaServerSaveSnapshot(h int, cpath, cpassphrase *C.cchar_t, compress int) int {
+	return guarded(func() int {
+		if alloc.resolve(h) == nil {
+			return -1
+		}
+
+		return setLastError(h, -1, errSnapshotUnsupported)
+	})
+}
+
+//export gpaServerLoadSnapshot
+func gpaServerLoadSnapshot(h int, cpath, cpassphrase *C.cchar_t) int {
+	return guarded(func() int {
+		if alloc.resolve(h) == nil {
+			return -1
+		}
+
+		return setLastError(h, -1, errSnapshotUnsupported)
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+
+func TestEncryptDecryptSnapshotRoundTrip(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		plaintext := []byte(`{"users":[{"id":"u1"}],"messages":[{"id":"m1","body":"hello world"}]}`)
+
+		data, err := encryptSnapshot(plaintext, "correct horse battery staple", compress)
+		if err != nil {
+			t.Fatalf("encryptSnapshot(compress=%v) error: %v", compress, err)
+		}
+
+		got, err := decryptSnapshot(data, "correct horse battery staple")
+		if err != nil {
+			t.Fatalf("decryptSnapshot(compress=%v) error: %v", compress, err)
+		}
+
+		if string(got) != string(plaintext) {
+			t.Fatalf("decryptSnapshot(compress=%v) = %q, want %q", compress, got, plaintext)
+		}
+	}
+}
+
+func TestDecryptSnapshotWrongPassphrase(t *testing.T) {
+	data, err := encryptSnapshot([]byte("secret state"), "right passphrase", false)
+	if err != nil {
+		t.Fatalf("encryptSnapshot error: %v", err)
+	}
+
+	if _, err := decryptSnapshot(data, "wrong passphrase"); err == nil {
+		t.Fatal("decryptSnapshot with wrong passphrase succeeded, want error")
+	}
+}
+
+func TestDecryptSnapshotCorruptedHeader(t *testing.T) {
+	data, err := encryptSnapshot([]byte("secret state"), "passphrase", false)
+	if err != nil {
+		t.Fatalf("encryptSnapshot error: %v", err)
+	}
+
+	cases := map[string][]byte{
+		"truncated":    data[:len(data)/2],
+		"bad magic":    append([]byte("XXXX"), data[4:]...),
+		"bad version":  withByteAt(data, 4, 0xff),
+		"bad cipherID": withByteAt(data, 5, 0xff),
+	}
+
+	for name, corrupted := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := decryptSnapshot(corrupted, "passphrase"); err == nil {
+				t.Fatalf("decryptSnapshot(%s) succeeded, want error", name)
+			}
+		})
+	}
+}
+
+func TestDecryptSnapshotTamperedCiphertext(t *testing.T) {
+	data, err := encryptSnapshot([]byte("secret state"), "passphrase", false)
+	if err != nil {
+		t.Fatalf("encryptSnapshot error: %v", err)
+	}
+
+	tampered := withByteAt(data, len(data)-1, data[len(data)-1]^0xff)
+
+	if _, err := decryptSnapshot(tampered, "passphrase"); err == nil {
+		t.Fatal("decryptSnapshot with tampered ciphertext succeeded, want error")
+	}
+}
+
+func withByteAt(data []byte, i int, b byte) []byte {
+	out := append([]byte(nil), data...)
+	out[i] = b
+	return out
+}
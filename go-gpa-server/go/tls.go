@@ -0,0 +1,70 @@
+package main
+
+/*
+#include "cgo_types.h"
+*/
+import "C"
+
+import (
+	"errors"
+
+	"github.com/ProtonMail/go-proton-api/server"
+)
+
+// errTLSCertOverrideUnsupported documents a real gap in the upstream
+// dependency: server.WithTLS is a bare on/off switch (server_builder.go),
+// with no option to supply a custom cert/key pair, no accessor for the CA
+// httptest.Server generates internally, and no way to rotate it mid-test.
+var errTLSCertOverrideUnsupported = errors.New("gpa: go-proton-api's server.Server only supports toggling TLS on/off; it has no custom cert/key, CA export, or rotation support to wire this to")
+
+// gpaServerNewTLS starts a server with TLS enabled. Supplying ccertPEM or
+// ckeyPEM fails with -1: upstream has no option to install a caller-supplied
+// certificate, so honoring only one side of the pair (or silently
+// generating an unrelated certificate instead) would be misleading. Pass
+// both as nil to get upstream's own self-signed certificate.
+//
+//export gpaServerNewTLS
+func gpaServerNewTLS(ccertPEM, ckeyPEM *C.cchar_t) int {
+	if ccertPEM != nil || ckeyPEM != nil {
+		return -1
+	}
+
+	var handle int
+	if status := guarded(func() int {
+		handle = alloc.alloc(server.New(server.WithTLS(true)))
+		return 0
+	}); status != 0 {
+		return -1
+	}
+
+	return handle
+}
+
+// gpaServerCACert always fails: server.Server exposes no accessor for the
+// certificate httptest.Server generates when TLS is enabled, so there is no
+// real PEM to return here. See errTLSCertOverrideUnsupported.
+//
+//export gpaServerCACert
+func gpaServerCACert(h int, outPEM **C.char, outLen *C.int) int {
+	return guarded(func() int {
+		if alloc.resolve(h) == nil {
+			return -1
+		}
+
+		return setLastError(h, -1, errTLSCertOverrideUnsupported)
+	})
+}
+
+// gpaServerRotateCert always fails: server.Server has no method to
+// regenerate its TLS certificate. See errTLSCertOverrideUnsupported.
+//
+//export gpaServerRotateCert
+func gpaServerRotateCert(h int) int {
+	return guarded(func() int {
+		if alloc.resolve(h) == nil {
+			return -1
+		}
+
+		return setLastError(h, -1, errTLSCertOverrideUnsupported)
+	})
+}